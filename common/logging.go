@@ -0,0 +1,240 @@
+package hummingbird
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/syslog"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// LogLevel is the severity of a single log record. The ordering matches
+// syslog's priority ordering (debug is least severe).
+type LogLevel int
+
+const (
+	LogLevelDebug LogLevel = iota
+	LogLevelInfo
+	LogLevelError
+)
+
+func (level LogLevel) String() string {
+	switch level {
+	case LogLevelDebug:
+		return "debug"
+	case LogLevelInfo:
+		return "info"
+	case LogLevelError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// LogSink renders and delivers one structured record. Swapping sinks is
+// how an operator chooses between syslog, stdout JSON lines, or a
+// rotating file without touching call sites.
+type LogSink interface {
+	Log(level LogLevel, msg string, fields map[string]interface{})
+}
+
+// Logger emits structured records (level, message, and arbitrary
+// key/value fields) to a pluggable LogSink. It replaces the old
+// hard-coded *syslog.Writer that WebRequest used to carry directly.
+type Logger struct {
+	sink   LogSink
+	fields map[string]interface{}
+}
+
+func NewLogger(sink LogSink) *Logger {
+	return &Logger{sink: sink}
+}
+
+// defaultLogger is the last-resort Logger for WebRequests that never had
+// one assigned (e.g. a WebContext built before a Router.Logger was set),
+// so LogFields has somewhere safe to write instead of panicking on a nil
+// *Logger.
+var defaultLogger = NewLogger(NewJSONSink(os.Stderr))
+
+func mergeFields(base map[string]interface{}, kv []interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(kv)/2)
+	for k, v := range base {
+		merged[k] = v
+	}
+	for i := 0; i+1 < len(kv); i += 2 {
+		if key, ok := kv[i].(string); ok {
+			merged[key] = kv[i+1]
+		}
+	}
+	return merged
+}
+
+// With returns a derived Logger that attaches the given key/value pairs
+// to every record it emits, in addition to any this Logger already
+// carries. Use it to thread request-scoped context (remote_addr, method,
+// ...) through a call chain without repeating it at every log call.
+func (logger *Logger) With(kv ...interface{}) *Logger {
+	return &Logger{sink: logger.sink, fields: mergeFields(logger.fields, kv)}
+}
+
+// LogFields emits a structured record at the given level with msg plus
+// this Logger's fields and any additional key/value pairs passed here.
+func (logger *Logger) LogFields(level LogLevel, msg string, kv ...interface{}) {
+	fields := logger.fields
+	if len(kv) > 0 {
+		fields = mergeFields(logger.fields, kv)
+	}
+	logger.sink.Log(level, msg, fields)
+}
+
+func (logger *Logger) Err(msg string)   { logger.LogFields(LogLevelError, msg) }
+func (logger *Logger) Info(msg string)  { logger.LogFields(LogLevelInfo, msg) }
+func (logger *Logger) Debug(msg string) { logger.LogFields(LogLevelDebug, msg) }
+
+// sortedFieldSuffix renders fields as " key=value" pairs in a stable
+// order, for sinks that produce single-line text rather than JSON.
+func sortedFieldSuffix(fields map[string]interface{}) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	suffix := ""
+	for _, k := range keys {
+		suffix += fmt.Sprintf(" %s=%v", k, fields[k])
+	}
+	return suffix
+}
+
+// SyslogSink reproduces Hummingbird's original logging behavior: plain
+// text lines shipped to syslog, with the transaction id rendered the same
+// "(txn:...)" way existing log-scraping tools already expect.
+type SyslogSink struct {
+	writer *syslog.Writer
+}
+
+func NewSyslogSink(tag string) (*SyslogSink, error) {
+	writer, err := syslog.New(syslog.LOG_INFO, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &SyslogSink{writer: writer}, nil
+}
+
+func (sink *SyslogSink) Log(level LogLevel, msg string, fields map[string]interface{}) {
+	line := msg
+	if txnId, ok := fields["txn_id"]; ok {
+		line += fmt.Sprintf(" (txn:%v)", txnId)
+	}
+	rest := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		if k != "txn_id" {
+			rest[k] = v
+		}
+	}
+	line += sortedFieldSuffix(rest)
+	switch level {
+	case LogLevelError:
+		sink.writer.Err(line)
+	case LogLevelDebug:
+		sink.writer.Debug(line)
+	default:
+		sink.writer.Info(line)
+	}
+}
+
+// JSONSink writes one JSON object per record to out - typically
+// os.Stdout, for log shipping into journald/Loki/ELK without any
+// "(txn:...)" scraping.
+type JSONSink struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func NewJSONSink(out io.Writer) *JSONSink {
+	return &JSONSink{out: out}
+}
+
+func (sink *JSONSink) Log(level LogLevel, msg string, fields map[string]interface{}) {
+	record := make(map[string]interface{}, len(fields)+3)
+	for k, v := range fields {
+		record[k] = v
+	}
+	record["level"] = level.String()
+	record["ts"] = time.Now().UTC().Format(time.RFC3339Nano)
+	record["msg"] = msg
+	line, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	sink.out.Write(append(line, '\n'))
+}
+
+// FileSink is a JSONSink over a file on disk, with simple size-based
+// rotation: once the file passes maxBytes, it's renamed with a timestamp
+// suffix and a fresh file is opened in its place.
+type FileSink struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	file     *os.File
+	size     int64
+	json     *JSONSink
+}
+
+func NewFileSink(path string, maxBytes int64) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	sink := &FileSink{path: path, maxBytes: maxBytes, file: file, size: info.Size()}
+	sink.json = NewJSONSink(sink)
+	return sink, nil
+}
+
+// Write implements io.Writer so FileSink can back its own JSONSink and
+// track the current file size as records are appended.
+func (sink *FileSink) Write(p []byte) (int, error) {
+	n, err := sink.file.Write(p)
+	sink.size += int64(n)
+	return n, err
+}
+
+// Log holds sink.mu for the rotation check, the JSONSink write it
+// delegates to, and the Write/size bookkeeping that write triggers (via
+// sink.json.Log -> sink.Write), so all three stay serialized under one
+// lock instead of racing between JSONSink's own mutex and this one.
+func (sink *FileSink) Log(level LogLevel, msg string, fields map[string]interface{}) {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if sink.maxBytes > 0 && sink.size >= sink.maxBytes {
+		sink.rotate()
+	}
+	sink.json.Log(level, msg, fields)
+}
+
+// rotate must be called with sink.mu held.
+func (sink *FileSink) rotate() {
+	sink.file.Close()
+	rotated := fmt.Sprintf("%s.%s", sink.path, time.Now().UTC().Format("20060102T150405"))
+	os.Rename(sink.path, rotated)
+	file, err := os.OpenFile(sink.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	sink.file = file
+	sink.size = 0
+}
@@ -0,0 +1,90 @@
+// Package metrics exposes Prometheus-format counters and histograms for
+// Hummingbird's HTTP servers, plus a middleware that feeds them from
+// every request routed through a hummingbird.Router, and an admin
+// http.Handler (RunServers' adminHandler) that serves /metrics,
+// /healthz, and /debug/pprof.
+package metrics
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	hummingbird "github.com/cweidenkeller/hummingbird/common"
+)
+
+var (
+	RequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "hb_http_requests_total",
+		Help: "Total HTTP requests processed, by method, status, and handler.",
+	}, []string{"method", "status", "handler"})
+
+	RequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "hb_http_request_duration_seconds",
+		Help: "HTTP request latency in seconds, by method and handler.",
+	}, []string{"method", "handler"})
+
+	InFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "hb_http_in_flight",
+		Help: "Number of HTTP requests currently being served.",
+	})
+
+	ResponseBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "hb_http_response_bytes",
+		Help: "Total bytes written in HTTP responses, by method and handler.",
+	}, []string{"method", "handler"})
+)
+
+func init() {
+	prometheus.MustRegister(RequestsTotal, RequestDuration, InFlight, ResponseBytes)
+}
+
+// Middleware returns a hummingbird.MiddlewareFunc that records request
+// counts, latency, in-flight gauge, and response bytes for every request
+// it wraps, reading the status and byte count WebWriter already captures.
+// handlerLabel identifies the route for aggregation; pass "" to fall back
+// to the request's path.
+func Middleware(handlerLabel string) hummingbird.MiddlewareFunc {
+	return func(next hummingbird.HandlerFunc) hummingbird.HandlerFunc {
+		return func(c *hummingbird.WebContext) {
+			InFlight.Inc()
+			defer InFlight.Dec()
+			start := time.Now()
+			next(c)
+
+			label := handlerLabel
+			if label == "" {
+				label = c.URL.Path
+			}
+			status := c.WebWriter.Status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			RequestsTotal.WithLabelValues(c.Method, strconv.Itoa(status), label).Inc()
+			RequestDuration.WithLabelValues(c.Method, label).Observe(time.Since(start).Seconds())
+			ResponseBytes.WithLabelValues(c.Method, label).Add(float64(c.WebWriter.BytesWritten))
+		}
+	}
+}
+
+// AdminHandler builds the mux RunServers' adminAddr listener serves:
+// Prometheus scraping at /metrics, a liveness check at /healthz, and
+// net/http/pprof's profiling endpoints under /debug/pprof.
+func AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	})
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
@@ -0,0 +1,191 @@
+package hummingbird
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var responseTemplate = "<html><h1>%s</h1><p>%s</p></html>"
+
+// statusText is the title/detail pair StandardResponse's default pages
+// are generated from, for every content type it knows how to render.
+type statusText struct {
+	title  string
+	detail string
+}
+
+var defaultStatusText = map[int]statusText{
+	100: {},
+	200: {},
+	201: {},
+	202: {"Accepted", "The request is accepted for processing."},
+	204: {},
+	206: {},
+	301: {"Moved Permanently", "The resource has moved permanently."},
+	302: {"Found", "The resource has moved temporarily."},
+	303: {"See Other", "The response to the request can be found under a different URI."},
+	304: {"Not Modified", ""},
+	307: {"Temporary Redirect", "The resource has moved temporarily."},
+	400: {"Bad Request", "The server could not comply with the request since it is either malformed or otherwise incorrect."},
+	401: {"Unauthorized", "This server could not verify that you are authorized to access the document you requested."},
+	402: {"Payment Required", "Access was denied for financial reasons."},
+	403: {"Forbidden", "Access was denied to this resource."},
+	404: {"Not Found", "The resource could not be found."},
+	405: {"Method Not Allowed", "The method is not allowed for this resource."},
+	406: {"Not Acceptable", "The resource is not available in a format acceptable to your browser."},
+	408: {"Request Timeout", "The server has waited too long for the request to be sent by the client."},
+	409: {"Conflict", "There was a conflict when trying to complete your request."},
+	410: {"Gone", "This resource is no longer available."},
+	411: {"Length Required", "Content-Length header required."},
+	412: {"Precondition Failed", "A precondition for this request was not met."},
+	413: {"Request Entity Too Large", "The body of your request was too large for this server."},
+	414: {"Request URI Too Long", "The request URI was too long for this server."},
+	415: {"Unsupported Media Type", "The request media type is not supported by this server."},
+	416: {"Requested Range Not Satisfiable", "The Range requested is not available."},
+	417: {"Expectation Failed", "Expectation failed."},
+	422: {"Unprocessable Entity", "Unable to process the contained instructions"},
+	499: {"Client Disconnect", "The client was disconnected during request."},
+	500: {"Internal Error", "The server has either erred or is incapable of performing the requested operation."},
+	501: {"Not Implemented", "The requested method is not implemented by this server."},
+	502: {"Bad Gateway", "Bad gateway."},
+	503: {"Service Unavailable", "The server is currently unavailable. Please try again at a later time."},
+	504: {"Gateway Timeout", "A timeout has occurred speaking to a backend server."},
+	507: {"Insufficient Storage", "There was not enough space to save the resource."},
+}
+
+// jsonError and xmlError are the shapes StandardResponse renders for
+// application/json and application/xml, the latter matching the
+// <Error> document Swift's own object/container/account servers return.
+type jsonError struct {
+	Error string `json:"error"`
+	Code  int    `json:"code"`
+}
+
+type xmlError struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    int      `xml:"Code"`
+	Message string   `xml:"Message"`
+}
+
+// ResponseTable holds, per status code, the representations StandardResponse
+// may choose between, keyed by content type. The zero value is empty;
+// DefaultResponseTable is pre-populated with Hummingbird's built-in pages.
+// A server that wants its own wording, a non-English translation, or to
+// drop a content type entirely can build its own table instead.
+type ResponseTable struct {
+	mu    sync.RWMutex
+	table map[int]map[string][]byte
+}
+
+func NewResponseTable() *ResponseTable {
+	return &ResponseTable{table: map[int]map[string][]byte{}}
+}
+
+// Register adds or replaces the representation served for status at
+// contentType.
+func (t *ResponseTable) Register(status int, contentType string, body []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.table == nil {
+		t.table = map[int]map[string][]byte{}
+	}
+	if t.table[status] == nil {
+		t.table[status] = map[string][]byte{}
+	}
+	t.table[status][contentType] = body
+}
+
+func (t *ResponseTable) representations(status int) map[string][]byte {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.table[status]
+}
+
+// DefaultResponseTable is the table StandardResponse falls back to when a
+// WebWriter doesn't have its own ResponseTable set.
+var DefaultResponseTable = NewResponseTable()
+
+// RegisterStandardResponse adds or replaces the representation served for
+// status at contentType in DefaultResponseTable.
+func RegisterStandardResponse(status int, contentType string, body []byte) {
+	DefaultResponseTable.Register(status, contentType, body)
+}
+
+func init() {
+	for status, text := range defaultStatusText {
+		if text.title == "" {
+			DefaultResponseTable.Register(status, "text/html", []byte{})
+			DefaultResponseTable.Register(status, "application/json", []byte{})
+			DefaultResponseTable.Register(status, "application/xml", []byte{})
+			DefaultResponseTable.Register(status, "text/plain", []byte{})
+			continue
+		}
+		DefaultResponseTable.Register(status, "text/html", []byte(fmt.Sprintf(responseTemplate, text.title, text.detail)))
+
+		jsonBody, _ := json.Marshal(jsonError{Error: text.detail, Code: status})
+		DefaultResponseTable.Register(status, "application/json", jsonBody)
+
+		xmlBody, _ := xml.Marshal(xmlError{Code: status, Message: text.detail})
+		DefaultResponseTable.Register(status, "application/xml", xmlBody)
+
+		DefaultResponseTable.Register(status, "text/plain", []byte(fmt.Sprintf("%s: %s", text.title, text.detail)))
+	}
+}
+
+// contentTypePreference is the order StandardResponse falls back to when
+// Accept is missing, "*/*", or names nothing this status has.
+var contentTypePreference = []string{"text/html", "application/json", "application/xml", "text/plain"}
+
+func negotiateContentType(accept string, available map[string][]byte) string {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		if mediaType == "" || mediaType == "*/*" {
+			continue
+		}
+		if _, ok := available[mediaType]; ok {
+			return mediaType
+		}
+	}
+	for _, mediaType := range contentTypePreference {
+		if _, ok := available[mediaType]; ok {
+			return mediaType
+		}
+	}
+	for mediaType := range available {
+		return mediaType
+	}
+	return "text/html"
+}
+
+// StandardResponse writes one of the registered boilerplate pages for
+// statusCode, choosing a representation via req's Accept header (falling
+// back to text/html, then whatever's registered). If w has its own
+// ResponseTable set, that's consulted instead of DefaultResponseTable.
+// HEAD requests get headers only, with Content-Length set to 0.
+func (w *WebWriter) StandardResponse(req *http.Request, statusCode int) {
+	table := w.ResponseTable
+	if table == nil {
+		table = DefaultResponseTable
+	}
+	available := table.representations(statusCode)
+	contentType := negotiateContentType(req.Header.Get("Accept"), available)
+	body := available[contentType]
+
+	// Headers must be set before WriteHeader - net/http flushes the header
+	// map as soon as WriteHeader is called, so setting them after is a
+	// silent no-op and the client sees Go's sniffed Content-Type instead.
+	w.Header().Set("Content-Type", contentType)
+	if req.Method == http.MethodHead {
+		w.Header().Set("Content-Length", "0")
+		w.WriteHeader(statusCode)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(int64(len(body)), 10))
+	w.WriteHeader(statusCode)
+	w.Write(body)
+}
@@ -0,0 +1,205 @@
+package hummingbird
+
+import (
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// WebContext is what handlers registered on a Router actually receive. It
+// embeds the existing WebRequest/WebWriter pair so all the helpers they
+// already provide (LogError, NillableFormValue, StandardResponse, ...)
+// keep working, and adds the bits routing needs: captured path params and
+// a per-request value bag for middleware to stash things like the
+// authenticated account.
+type WebContext struct {
+	WebRequest
+	WebWriter
+	Params map[string]string
+
+	values map[string]interface{}
+}
+
+func (c *WebContext) Set(key string, value interface{}) {
+	if c.values == nil {
+		c.values = make(map[string]interface{})
+	}
+	c.values[key] = value
+}
+
+func (c *WebContext) Get(key string) (interface{}, bool) {
+	value, ok := c.values[key]
+	return value, ok
+}
+
+// HandlerFunc is the signature routes and middleware are registered with.
+type HandlerFunc func(*WebContext)
+
+// MiddlewareFunc wraps a HandlerFunc to run code before and/or after it.
+type MiddlewareFunc func(HandlerFunc) HandlerFunc
+
+// WrapHandler adapts a plain http.Handler so it can be registered on a
+// Router alongside native HandlerFuncs, for handlers that don't need
+// params or the middleware chain's WebContext.
+func WrapHandler(handler http.Handler) HandlerFunc {
+	return func(c *WebContext) {
+		handler.ServeHTTP(&c.WebWriter, c.WebRequest.Request)
+	}
+}
+
+// Recovery is the built-in middleware that replaces the old
+// WebRequest.LogPanics pattern: it recovers a panic anywhere further down
+// the chain, logs it with the stack trace, and answers with a 500 instead
+// of letting net/http tear down the connection.
+func Recovery(next HandlerFunc) HandlerFunc {
+	return func(c *WebContext) {
+		defer func() {
+			if e := recover(); e != nil {
+				c.LogError("PANIC: %s: %s", e, debug.Stack())
+				c.StandardResponse(c.WebRequest.Request, http.StatusInternalServerError)
+			}
+		}()
+		next(c)
+	}
+}
+
+// routeNode is one segment of a registered path pattern. Static children
+// are matched by exact segment text; at most one param child (":name")
+// and one wildcard child ("*name") may exist per node, matching gin's
+// pattern rules.
+type routeNode struct {
+	children     map[string]*routeNode
+	paramChild   *routeNode
+	paramName    string
+	wildcard     *routeNode
+	wildcardName string
+	handler      HandlerFunc
+}
+
+// Router is a small gin-inspired request router: it dispatches on method
+// and path pattern (with :param and *wildcard captures), materializes a
+// *WebContext per request, and runs it through a Use()-registered
+// middleware chain before handing it to the matched handler.
+type Router struct {
+	trees      map[string]*routeNode
+	middleware []MiddlewareFunc
+	NotFound   HandlerFunc
+	// Logger is attached to every request's WebContext before the
+	// middleware chain runs, so Recovery (and any handler) can log
+	// without needing to nil-check WebRequest.Logger itself.
+	Logger *Logger
+}
+
+func NewRouter() *Router {
+	return &Router{
+		trees:    make(map[string]*routeNode),
+		NotFound: func(c *WebContext) { c.StandardResponse(c.WebRequest.Request, http.StatusNotFound) },
+		Logger:   defaultLogger,
+	}
+}
+
+// Use appends middleware to the chain every matched route runs through,
+// in the order given - the first middleware added is outermost.
+func (router *Router) Use(middleware ...MiddlewareFunc) {
+	router.middleware = append(router.middleware, middleware...)
+}
+
+func splitPath(path string) []string {
+	trimmed := strings.Trim(path, "/")
+	if trimmed == "" {
+		return nil
+	}
+	return strings.Split(trimmed, "/")
+}
+
+func (router *Router) Handle(method, path string, handler HandlerFunc) {
+	root, ok := router.trees[method]
+	if !ok {
+		root = &routeNode{}
+		router.trees[method] = root
+	}
+	node := root
+	for _, segment := range splitPath(path) {
+		switch {
+		case strings.HasPrefix(segment, ":"):
+			if node.paramChild == nil {
+				node.paramChild = &routeNode{}
+				node.paramName = segment[1:]
+			}
+			node = node.paramChild
+		case strings.HasPrefix(segment, "*"):
+			if node.wildcard == nil {
+				node.wildcard = &routeNode{}
+				node.wildcardName = segment[1:]
+			}
+			node = node.wildcard
+		default:
+			if node.children == nil {
+				node.children = make(map[string]*routeNode)
+			}
+			child, ok := node.children[segment]
+			if !ok {
+				child = &routeNode{}
+				node.children[segment] = child
+			}
+			node = child
+		}
+	}
+	node.handler = handler
+}
+
+func (router *Router) GET(path string, handler HandlerFunc)    { router.Handle(http.MethodGet, path, handler) }
+func (router *Router) PUT(path string, handler HandlerFunc)    { router.Handle(http.MethodPut, path, handler) }
+func (router *Router) POST(path string, handler HandlerFunc)   { router.Handle(http.MethodPost, path, handler) }
+func (router *Router) DELETE(path string, handler HandlerFunc) { router.Handle(http.MethodDelete, path, handler) }
+func (router *Router) HEAD(path string, handler HandlerFunc)   { router.Handle(http.MethodHead, path, handler) }
+func (router *Router) COPY(path string, handler HandlerFunc)   { router.Handle("COPY", path, handler) }
+
+// match walks the tree for method, preferring a static segment match over
+// a param capture over a wildcard, and returns the handler plus any
+// captured params. A *wildcard segment, once reached, swallows the rest
+// of the path in one capture.
+func (router *Router) match(method, path string) (HandlerFunc, map[string]string) {
+	root, ok := router.trees[method]
+	if !ok {
+		return nil, nil
+	}
+	segments := splitPath(path)
+	params := map[string]string{}
+	node := root
+	for i, segment := range segments {
+		if child, ok := node.children[segment]; ok {
+			node = child
+			continue
+		}
+		if node.paramChild != nil {
+			params[node.paramName] = segment
+			node = node.paramChild
+			continue
+		}
+		if node.wildcard != nil {
+			params[node.wildcardName] = strings.Join(segments[i:], "/")
+			return node.wildcard.handler, params
+		}
+		return nil, nil
+	}
+	return node.handler, params
+}
+
+func (router *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	handler, params := router.match(req.Method, req.URL.Path)
+	if handler == nil {
+		handler = router.NotFound
+		params = map[string]string{}
+	}
+	for i := len(router.middleware) - 1; i >= 0; i-- {
+		handler = router.middleware[i](handler)
+	}
+	context := &WebContext{
+		WebRequest: WebRequest{Request: req, Start: time.Now(), Logger: router.Logger},
+		WebWriter:  WebWriter{ResponseWriter: w},
+		Params:     params,
+	}
+	handler(context)
+}
@@ -1,66 +1,34 @@
 package hummingbird
 
 import (
+	"crypto/tls"
 	"fmt"
-	"log/syslog"
 	"net"
 	"net/http"
 	"os"
+	"os/exec"
 	"os/signal"
 	"path/filepath"
 	"runtime/debug"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
-)
 
-var responseTemplate = "<html><h1>%s</h1><p>%s</p></html>"
-
-var responseBodies = map[int]string{
-	100: "",
-	200: "",
-	201: "",
-	202: fmt.Sprintf(responseTemplate, "Accepted", "The request is accepted for processing."),
-	204: "",
-	206: "",
-	301: fmt.Sprintf(responseTemplate, "Moved Permanently", "The resource has moved permanently."),
-	302: fmt.Sprintf(responseTemplate, "Found", "The resource has moved temporarily."),
-	303: fmt.Sprintf(responseTemplate, "See Other", "The response to the request can be found under a different URI."),
-	304: fmt.Sprintf(responseTemplate, "Not Modified", ""),
-	307: fmt.Sprintf(responseTemplate, "Temporary Redirect", "The resource has moved temporarily."),
-	400: fmt.Sprintf(responseTemplate, "Bad Request", "The server could not comply with the request since it is either malformed or otherwise incorrect."),
-	401: fmt.Sprintf(responseTemplate, "Unauthorized", "This server could not verify that you are authorized to access the document you requested."),
-	402: fmt.Sprintf(responseTemplate, "Payment Required", "Access was denied for financial reasons."),
-	403: fmt.Sprintf(responseTemplate, "Forbidden", "Access was denied to this resource."),
-	404: fmt.Sprintf(responseTemplate, "Not Found", "The resource could not be found."),
-	405: fmt.Sprintf(responseTemplate, "Method Not Allowed", "The method is not allowed for this resource."),
-	406: fmt.Sprintf(responseTemplate, "Not Acceptable", "The resource is not available in a format acceptable to your browser."),
-	408: fmt.Sprintf(responseTemplate, "Request Timeout", "The server has waited too long for the request to be sent by the client."),
-	409: fmt.Sprintf(responseTemplate, "Conflict", "There was a conflict when trying to complete your request."),
-	410: fmt.Sprintf(responseTemplate, "Gone", "This resource is no longer available."),
-	411: fmt.Sprintf(responseTemplate, "Length Required", "Content-Length header required."),
-	412: fmt.Sprintf(responseTemplate, "Precondition Failed", "A precondition for this request was not met."),
-	413: fmt.Sprintf(responseTemplate, "Request Entity Too Large", "The body of your request was too large for this server."),
-	414: fmt.Sprintf(responseTemplate, "Request URI Too Long", "The request URI was too long for this server."),
-	415: fmt.Sprintf(responseTemplate, "Unsupported Media Type", "The request media type is not supported by this server."),
-	416: fmt.Sprintf(responseTemplate, "Requested Range Not Satisfiable", "The Range requested is not available."),
-	417: fmt.Sprintf(responseTemplate, "Expectation Failed", "Expectation failed."),
-	422: fmt.Sprintf(responseTemplate, "Unprocessable Entity", "Unable to process the contained instructions"),
-	499: fmt.Sprintf(responseTemplate, "Client Disconnect", "The client was disconnected during request."),
-	500: fmt.Sprintf(responseTemplate, "Internal Error", "The server has either erred or is incapable of performing the requested operation."),
-	501: fmt.Sprintf(responseTemplate, "Not Implemented", "The requested method is not implemented by this server."),
-	502: fmt.Sprintf(responseTemplate, "Bad Gateway", "Bad gateway."),
-	503: fmt.Sprintf(responseTemplate, "Service Unavailable", "The server is currently unavailable. Please try again at a later time."),
-	504: fmt.Sprintf(responseTemplate, "Gateway Timeout", "A timeout has occurred speaking to a backend server."),
-	507: fmt.Sprintf(responseTemplate, "Insufficient Storage", "There was not enough space to save the resource."),
-}
+	"golang.org/x/net/http2"
+)
 
 // ResponseWriter that saves its status - used for logging.
 
 type WebWriter struct {
 	http.ResponseWriter
-	Status int
+	Status       int
+	BytesWritten int64
+	// ResponseTable, if set, overrides DefaultResponseTable for this
+	// writer's calls to StandardResponse - e.g. a non-English deployment
+	// or an API-only server that wants to drop text/html entirely.
+	ResponseTable *ResponseTable
 }
 
 func (w *WebWriter) WriteHeader(status int) {
@@ -68,20 +36,21 @@ func (w *WebWriter) WriteHeader(status int) {
 	w.Status = status
 }
 
+func (w *WebWriter) Write(p []byte) (int, error) {
+	if w.Status == 0 {
+		w.Status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.BytesWritten += int64(n)
+	return n, err
+}
+
 func (w *WebWriter) CopyResponseHeaders(src *http.Response) {
 	for key := range src.Header {
 		w.Header().Set(key, src.Header.Get(key))
 	}
 }
 
-func (w *WebWriter) StandardResponse(statusCode int) {
-	w.WriteHeader(statusCode)
-	body := responseBodies[statusCode]
-	w.Header().Set("Content-Type", "text/html")
-	w.Header().Set("Content-Length", strconv.FormatInt(int64(len(body)), 10))
-	w.Write([]byte(body))
-}
-
 // http.Request that also contains swift-specific info about the request
 
 type WebRequest struct {
@@ -89,7 +58,7 @@ type WebRequest struct {
 	TransactionId string
 	XTimestamp    string
 	Start         time.Time
-	Logger        *syslog.Writer
+	Logger        *Logger
 }
 
 func (r *WebRequest) CopyRequestHeaders(dst *http.Request) {
@@ -111,21 +80,33 @@ func (r *WebRequest) NillableFormValue(key string) *string {
 	}
 }
 
+// LogFields emits a structured record via r.Logger, automatically
+// attaching this request's transaction id to every record. Falls back to
+// defaultLogger if r.Logger was never set, so a bare WebRequest (or a
+// panic inside Recovery) can't crash trying to log through a nil Logger.
+func (r WebRequest) LogFields(level LogLevel, msg string, kv ...interface{}) {
+	logger := r.Logger
+	if logger == nil {
+		logger = defaultLogger
+	}
+	logger.LogFields(level, msg, append([]interface{}{"txn_id", r.TransactionId}, kv...)...)
+}
+
 func (r WebRequest) LogError(format string, args ...interface{}) {
-	r.Logger.Err(fmt.Sprintf(format, args...) + " (txn:" + r.TransactionId + ")")
+	r.LogFields(LogLevelError, fmt.Sprintf(format, args...))
 }
 
 func (r WebRequest) LogInfo(format string, args ...interface{}) {
-	r.Logger.Info(fmt.Sprintf(format, args...) + " (txn:" + r.TransactionId + ")")
+	r.LogFields(LogLevelInfo, fmt.Sprintf(format, args...))
 }
 
 func (r WebRequest) LogDebug(format string, args ...interface{}) {
-	r.Logger.Debug(fmt.Sprintf(format, args...) + " (txn:" + r.TransactionId + ")")
+	r.LogFields(LogLevelDebug, fmt.Sprintf(format, args...))
 }
 
 func (r WebRequest) LogPanics() {
 	if e := recover(); e != nil {
-		r.Logger.Err(fmt.Sprintf("PANIC: %s: %s", e, debug.Stack()) + " (txn:" + r.TransactionId + ")")
+		r.LogFields(LogLevelError, fmt.Sprintf("PANIC: %s: %s", e, debug.Stack()))
 	}
 }
 
@@ -133,6 +114,7 @@ type LoggingContext interface {
 	LogError(format string, args ...interface{})
 	LogInfo(format string, args ...interface{})
 	LogDebug(format string, args ...interface{})
+	LogFields(level LogLevel, msg string, kv ...interface{})
 }
 
 /* http.Server that knows how to shut down gracefully */
@@ -140,15 +122,29 @@ type LoggingContext interface {
 type HummingbirdServer struct {
 	http.Server
 	Listener net.Listener
-	wg       sync.WaitGroup
+	// RawListener is the plain TCP listener backing Listener, even when
+	// Listener is wrapped for TLS. It's kept around so a SIGHUP restart
+	// can hand the underlying fd to the child via os/exec's ExtraFiles.
+	RawListener *net.TCPListener
+	wg          sync.WaitGroup
+	inFlight    int64
 }
 
-func (srv *HummingbirdServer) ConnStateChange(conn net.Conn, state http.ConnState) {
-	if state == http.StateNew {
+// trackInFlight wraps the real handler so BeginShutdown/Wait can drain
+// outstanding requests rather than outstanding connections. This matters
+// for HTTP/2, where a single connection can carry many concurrent logical
+// requests, so counting via ConnState alone would let a shutdown race
+// ahead of requests still in flight on a connection that never closes.
+func (srv *HummingbirdServer) trackInFlight(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&srv.inFlight, 1)
 		srv.wg.Add(1)
-	} else if state == http.StateClosed {
-		srv.wg.Done()
-	}
+		defer func() {
+			srv.wg.Done()
+			atomic.AddInt64(&srv.inFlight, -1)
+		}()
+		handler.ServeHTTP(w, r)
+	})
 }
 
 func (srv *HummingbirdServer) BeginShutdown() {
@@ -160,48 +156,227 @@ func (srv *HummingbirdServer) Wait() {
 	srv.wg.Wait()
 }
 
+// TLSConfig describes how to load a TLS cert/key pair for a listener,
+// optionally alongside additional SNI certs for virtual hosting.
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	// SNICerts maps additional cert/key pairs, keyed by hostname, for
+	// servers that terminate TLS for more than one name on the same port.
+	SNICerts map[string][2]string
+}
+
+func (c *TLSConfig) buildTLSConfig() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading TLS cert/key: %s", err)
+	}
+	certs := []tls.Certificate{cert}
+	nameToCert := map[string]*tls.Certificate{}
+	for name, pair := range c.SNICerts {
+		sniCert, err := tls.LoadX509KeyPair(pair[0], pair[1])
+		if err != nil {
+			return nil, fmt.Errorf("loading SNI cert/key for %s: %s", name, err)
+		}
+		certs = append(certs, sniCert)
+		nameToCert[name] = &certs[len(certs)-1]
+	}
+	config := &tls.Config{
+		Certificates: certs,
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+	if len(nameToCert) > 0 {
+		config.GetCertificate = func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			if cert, ok := nameToCert[hello.ServerName]; ok {
+				return cert, nil
+			}
+			return &certs[0], nil
+		}
+	}
+	return config, nil
+}
+
+// listenFdsEnvVar carries the number of listening sockets a re-exec'd
+// child inherited from its parent, in the same order the parent opened
+// them (which is also the sorted glob order of the *.conf files, so
+// parent and child agree on it without needing to exchange addresses).
+const listenFdsEnvVar = "HUMMINGBIRD_LISTEN_FDS"
+
+// firstInheritedFd is the first fd available to the child beyond the
+// standard stdin/stdout/stderr triplet; os/exec.Cmd.ExtraFiles appends
+// starting here.
+const firstInheritedFd = 3
+
+func inheritedListeners() []*net.TCPListener {
+	count, _ := strconv.Atoi(os.Getenv(listenFdsEnvVar))
+	listeners := make([]*net.TCPListener, 0, count)
+	for i := 0; i < count; i++ {
+		f := os.NewFile(uintptr(firstInheritedFd+i), fmt.Sprintf("listener%d", i))
+		ln, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			break
+		}
+		tcpLn, ok := ln.(*net.TCPListener)
+		if !ok {
+			break
+		}
+		listeners = append(listeners, tcpLn)
+	}
+	return listeners
+}
+
+// reexecWithListeners forks and execs the running binary, handing it the
+// raw listening sockets via ExtraFiles so the child can start accepting
+// connections before the parent gives up its own. If re-exec fails for
+// any reason, the parent logs to stderr and continues its own shutdown
+// rather than dropping the listeners outright.
+// reexecWithListeners hands the child every raw TCP listener the parent
+// holds, in a fixed order: the configured servers first (matching
+// configFiles' glob order), then the admin listener if any, so both
+// sides agree on fd layout without exchanging addresses. adminListener
+// may be nil if no admin listener was configured or it failed to bind.
+func reexecWithListeners(servers []*HummingbirdServer, adminListener *net.TCPListener) {
+	execPath, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "graceful restart: couldn't find executable: %s\n", err)
+		return
+	}
+	rawListeners := make([]*net.TCPListener, 0, len(servers)+1)
+	for _, srv := range servers {
+		rawListeners = append(rawListeners, srv.RawListener)
+	}
+	if adminListener != nil {
+		rawListeners = append(rawListeners, adminListener)
+	}
+	extraFiles := make([]*os.File, 0, len(rawListeners))
+	for _, ln := range rawListeners {
+		f, err := ln.File()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "graceful restart: couldn't dup listener: %s\n", err)
+			return
+		}
+		extraFiles = append(extraFiles, f)
+	}
+	cmd := exec.Command(execPath, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = extraFiles
+	cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%d", listenFdsEnvVar, len(extraFiles)))
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "graceful restart: couldn't start child: %s\n", err)
+		return
+	}
+	for _, f := range extraFiles {
+		f.Close()
+	}
+}
+
+// gracefulShutdown closes every listener, then gives in-flight requests up
+// to 5 minutes to finish before forcing an exit.
+func gracefulShutdown(servers []*HummingbirdServer) {
+	for _, srv := range servers {
+		srv.BeginShutdown()
+	}
+	go func() {
+		time.Sleep(time.Minute * 5)
+		os.Exit(0)
+	}()
+	for _, srv := range servers {
+		srv.Wait()
+		time.Sleep(time.Second * 5)
+	}
+}
+
 /*
 	SIGHUP - graceful restart
 	SIGINT - graceful shutdown
 	SIGTERM, SIGQUIT - immediate shutdown
 
 	Graceful shutdown/restart gives any open connections 5 minutes to complete, then exits.
+
+	On SIGHUP, the listening sockets are passed to a freshly exec'd copy of
+	the running binary, which starts accepting connections immediately; the
+	old process then drains and exits exactly as it would for SIGINT.
+
+	adminAddr/adminHandler, when adminAddr is non-empty, start a separate
+	plain-HTTP listener (typically serving the metrics package's /metrics,
+	/healthz, and /debug/pprof handlers) alongside the configured servers.
+	RunServers itself stays agnostic of what the admin handler contains, so
+	common doesn't need to depend on the metrics package.
 */
-func RunServers(configFile string, GetServer func(string) (string, int, http.Handler)) {
+func RunServers(configFile string, GetServer func(string) (string, int, http.Handler, *TLSConfig), adminAddr string, adminHandler http.Handler) {
 	var servers []*HummingbirdServer
 	configFiles, err := filepath.Glob(fmt.Sprintf("%s/*.conf", configFile))
 	if err != nil || len(configFiles) <= 0 {
 		configFiles = []string{configFile}
 	}
-	for _, configFile := range configFiles {
-		ip, port, handler := GetServer(configFile)
-		sock, err := net.Listen("tcp", fmt.Sprintf("%s:%d", ip, port))
-		if err != nil {
-			panic("Error listening on socket!")
+	// inherited is indexed in the same fixed order reexecWithListeners
+	// writes it in: the configured servers, then the admin listener.
+	inherited := inheritedListeners()
+	for i, configFile := range configFiles {
+		ip, port, handler, tlsConf := GetServer(configFile)
+		var tcpSock *net.TCPListener
+		if i < len(inherited) {
+			tcpSock = inherited[i]
+		} else {
+			ln, err := net.Listen("tcp", fmt.Sprintf("%s:%d", ip, port))
+			if err != nil {
+				panic("Error listening on socket!")
+			}
+			tcpSock = ln.(*net.TCPListener)
 		}
 		srv := HummingbirdServer{}
-		srv.Handler = handler
-		srv.ConnState = srv.ConnStateChange
+		srv.Handler = srv.trackInFlight(handler)
+		srv.RawListener = tcpSock
+		var sock net.Listener = tcpSock
+		if tlsConf != nil {
+			config, err := tlsConf.buildTLSConfig()
+			if err != nil {
+				panic(fmt.Sprintf("Error configuring TLS: %s", err))
+			}
+			srv.TLSConfig = config
+			if err := http2.ConfigureServer(&srv.Server, nil); err != nil {
+				panic(fmt.Sprintf("Error configuring HTTP/2: %s", err))
+			}
+			sock = tls.NewListener(tcpSock, config)
+		}
 		srv.Listener = sock
 		go srv.Serve(sock)
 		servers = append(servers, &srv)
 	}
 
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT)
-	s := <-c
-	if s == syscall.SIGINT {
-		for _, srv := range servers {
-			srv.BeginShutdown()
+	var adminListener *net.TCPListener
+	if adminAddr != "" && adminHandler != nil {
+		if len(configFiles) < len(inherited) {
+			adminListener = inherited[len(configFiles)]
+		} else if ln, err := net.Listen("tcp", adminAddr); err != nil {
+			fmt.Fprintf(os.Stderr, "Error starting admin listener on %s: %s\n", adminAddr, err)
+		} else {
+			adminListener = ln.(*net.TCPListener)
 		}
-		go func() {
-			time.Sleep(time.Minute * 5)
-			os.Exit(0)
-		}()
-		for _, srv := range servers {
-			srv.Wait()
-			time.Sleep(time.Second * 5)
+		if adminListener != nil {
+			go func() {
+				if err := http.Serve(adminListener, adminHandler); err != nil {
+					fmt.Fprintf(os.Stderr, "admin listener on %s stopped: %s\n", adminAddr, err)
+				}
+			}()
 		}
 	}
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP, syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT)
+	s := <-c
+	switch s {
+	case syscall.SIGHUP:
+		reexecWithListeners(servers, adminListener)
+		gracefulShutdown(servers)
+	case syscall.SIGINT:
+		gracefulShutdown(servers)
+	}
+	if adminListener != nil {
+		adminListener.Close()
+	}
 	os.Exit(0)
 }